@@ -5,8 +5,7 @@
 package main
 
 import (
-	"errors"
-	"io"
+	"fmt"
 	"log"
 	"os"
 
@@ -31,12 +30,9 @@ func cmdVerify(file string) {
 		log.Fatal(err)
 	}
 	defer ctd.Close()
-	headb := make([]byte, cryptdatum.HeaderSize)
 
-	if _, err := ctd.Read(headb); err != nil && !errors.Is(err, io.EOF) {
-		log.Fatal(err)
-	}
-	if !cryptdatum.VerifyHeader(headb) {
+	if _, err := cryptdatum.Verify(ctd); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 	os.Exit(0)
@@ -0,0 +1,31 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+// Package flate registers the Cryptdatum Compressor/Decompressor pair for
+// DEFLATE-compressed payloads, backed by the standard library's
+// compress/flate, under ID. Importing this package for its side effect is
+// enough to make ID usable as a CompressionAlg value:
+//
+//	import _ "howijd.network/lib/cryptdatum/compress/flate"
+package flate
+
+import (
+	"compress/flate"
+	"io"
+
+	"howijd.network/lib/cryptdatum"
+)
+
+// ID is the CompressionAlg value datums compressed with this package use.
+const ID uint16 = 1
+
+func init() {
+	cryptdatum.RegisterCompressor(ID, func(w io.Writer) io.WriteCloser {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	})
+	cryptdatum.RegisterDecompressor(ID, func(r io.Reader) io.ReadCloser {
+		return flate.NewReader(r)
+	})
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+// Package zstd registers the Cryptdatum Compressor/Decompressor pair for
+// zstd-compressed payloads, backed by github.com/klauspost/compress/zstd,
+// under ID. Importing this package for its side effect is enough to make
+// ID usable as a CompressionAlg value:
+//
+//	import _ "howijd.network/lib/cryptdatum/compress/zstd"
+package zstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"howijd.network/lib/cryptdatum"
+)
+
+// ID is the CompressionAlg value datums compressed with this package use.
+const ID uint16 = 2
+
+func init() {
+	cryptdatum.RegisterCompressor(ID, compress)
+	cryptdatum.RegisterDecompressor(ID, decompress)
+}
+
+func compress(w io.Writer) io.WriteCloser {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return errWriteCloser{err}
+	}
+	return zw
+}
+
+func decompress(r io.Reader) io.ReadCloser {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return errReadCloser{err}
+	}
+	return zr.IOReadCloser()
+}
+
+// errReadCloser is returned when the underlying *zstd.Decoder fails to
+// initialize, so the error surfaces on the first Read instead of being
+// dropped by RegisterDecompressor's error-less factory signature.
+type errReadCloser struct{ err error }
+
+func (e errReadCloser) Read([]byte) (int, error) { return 0, e.err }
+func (e errReadCloser) Close() error             { return nil }
+
+// errWriteCloser is returned when the underlying *zstd.Encoder fails to
+// initialize, so the error surfaces on the first Write instead of being
+// dropped by RegisterCompressor's error-less factory signature.
+type errWriteCloser struct{ err error }
+
+func (e errWriteCloser) Write([]byte) (int, error) { return 0, e.err }
+func (e errWriteCloser) Close() error              { return e.err }
@@ -0,0 +1,47 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package zstd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestRoundtrip(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("hello cryptdatum zstd "), 1000)
+
+	var compressed bytes.Buffer
+	cw := compress(&compressed)
+	if _, err := cw.Write(plaintext); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if compressed.Len() >= len(plaintext) {
+		t.Errorf("expected compressed size (%d) to be smaller than plaintext (%d)", compressed.Len(), len(plaintext))
+	}
+
+	cr := decompress(bytes.NewReader(compressed.Bytes()))
+	got, err := io.ReadAll(cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cr.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decompressed payload does not match plaintext")
+	}
+}
+
+func TestDecompressInvalidStreamReturnsError(t *testing.T) {
+	cr := decompress(bytes.NewReader([]byte("not a zstd stream")))
+	if _, err := io.ReadAll(cr); err == nil {
+		t.Errorf("expected an error decompressing a non-zstd stream")
+	}
+}
@@ -179,30 +179,27 @@ func HasValidHeader(data []byte) bool {
 		return false
 	}
 
-	// DatumEmpty and DatumDraft
-	if flags&DatumEmpty != 0 {
-		// Size field must be set
-		if binary.LittleEndian.Uint64(data[38:46]) < 1 {
-			return false
-		}
+	// DatumEmpty Size field must be set
+	if flags&DatumEmpty != 0 && binary.LittleEndian.Uint64(data[38:46]) < 1 {
+		return false
+	}
 
-		// DatumCompressed compression algorithm must be set
-		if flags&DatumCompressed != 0 && binary.LittleEndian.Uint16(data[46:48]) < 1 {
-			return false
-		}
-		// DatumEncrypted encryption algorithm must be set
-		if flags&DatumEncrypted != 0 && binary.LittleEndian.Uint16(data[48:50]) < 1 {
-			return false
-		}
-		// DatumExtractable payl;oad can be extracted then filename must be set
-		if flags&DatumExtractable != 0 && bytes.Equal(data[50:58], empty[:]) {
-			return false
-		}
+	// DatumCompressed compression algorithm must be set
+	if flags&DatumCompressed != 0 && binary.LittleEndian.Uint16(data[46:48]) < 1 {
+		return false
+	}
+	// DatumEncrypted encryption algorithm must be set
+	if flags&DatumEncrypted != 0 && binary.LittleEndian.Uint16(data[48:50]) < 1 {
+		return false
+	}
+	// DatumExtractable payload can be extracted then filename must be set
+	if flags&DatumExtractable != 0 && bytes.Equal(data[56:64], empty[:]) {
+		return false
 	}
 
 	// DatumSigned then Signature Type must be also set
 	// however value of the signature Size may depend on Signature Type
-	if flags&DatumSigned != 0 && binary.LittleEndian.Uint16(data[58:60]) < 1 {
+	if flags&DatumSigned != 0 && binary.LittleEndian.Uint16(data[50:52]) < 1 {
 		return false
 	}
 	return true
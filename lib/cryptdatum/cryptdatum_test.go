@@ -6,8 +6,9 @@ package cryptdatum
 
 import (
 	"encoding/binary"
-	"os"
 	"testing"
+
+	"howijd.network/lib/cryptdatum/internal/obscuretestdata"
 )
 
 func TestHasValidHeaderMagic(t *testing.T) {
@@ -60,7 +61,7 @@ func TestHasValidHeaderDelimiter(t *testing.T) {
 }
 
 func TestHasValidHeaderSpecV1(t *testing.T) {
-	head, err := os.ReadFile("testdata/v1/has-aligned-header.cdt")
+	head, err := obscuretestdata.ReadFile("testdata/v1/has-aligned-header.cdt")
 	if err != nil {
 		t.Error(err)
 	}
@@ -68,3 +69,33 @@ func TestHasValidHeaderSpecV1(t *testing.T) {
 		t.Errorf("expected header to be invalid")
 	}
 }
+
+func TestHasValidHeaderSparse(t *testing.T) {
+	head, err := obscuretestdata.ReadFile("testdata/v1/sparse.cdt")
+	if err != nil {
+		t.Error(err)
+	}
+	if !HasValidHeader(head) {
+		t.Errorf("expected header to be valid")
+	}
+}
+
+func TestHasValidHeaderTruncatedFixture(t *testing.T) {
+	head, err := obscuretestdata.ReadFile("testdata/v1/truncated.cdt")
+	if err != nil {
+		t.Error(err)
+	}
+	if HasValidHeader(head) {
+		t.Errorf("expected truncated header to be invalid")
+	}
+}
+
+func TestHasValidHeaderTamperedFixture(t *testing.T) {
+	head, err := obscuretestdata.ReadFile("testdata/v1/tampered.cdt")
+	if err != nil {
+		t.Error(err)
+	}
+	if HasValidHeader(head) {
+		t.Errorf("expected tampered header to be invalid")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+// Package aesgcm registers the Cryptdatum Encrypter/Decrypter pair for
+// AES in GCM mode, backed by the standard library's crypto/aes and
+// crypto/cipher, under ID. Importing this package for its side effect is
+// enough to make ID usable as an EncryptionAlg value:
+//
+//	import _ "howijd.network/lib/cryptdatum/encrypt/aesgcm"
+//
+// A fresh nonce is generated for every datum and written as a preamble on
+// the ciphertext stream itself, so the caller-supplied iv is ignored: GCM's
+// security collapses under key+nonce reuse, and there is no way for this
+// package to enforce that a caller-managed nonce is only ever used once.
+//
+// The payload is sealed as a single AEAD message on Close, so it must fit
+// in memory; datums too large for that should set DatumStreamable instead.
+package aesgcm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+
+	"howijd.network/lib/cryptdatum"
+)
+
+// ID is the EncryptionAlg value datums encrypted with this package use.
+const ID uint16 = 1
+
+func init() {
+	cryptdatum.RegisterEncrypter(ID, encrypt)
+	cryptdatum.RegisterDecrypter(ID, decrypt)
+}
+
+func encrypt(key, iv []byte, w io.Writer) (io.WriteCloser, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(nonce); err != nil {
+		return nil, err
+	}
+	return &writer{gcm: gcm, nonce: nonce, w: w}, nil
+}
+
+func decrypt(key, iv []byte, r io.Reader) (io.ReadCloser, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return nil, err
+	}
+	return &reader{gcm: gcm, nonce: nonce, r: r}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writer buffers payload bytes and seals them as a single AEAD message to w
+// on Close.
+type writer struct {
+	gcm   cipher.AEAD
+	nonce []byte
+	w     io.Writer
+	buf   []byte
+}
+
+func (cw *writer) Write(p []byte) (int, error) {
+	cw.buf = append(cw.buf, p...)
+	return len(p), nil
+}
+
+func (cw *writer) Close() error {
+	_, err := cw.w.Write(cw.gcm.Seal(nil, cw.nonce, cw.buf, nil))
+	return err
+}
+
+// reader opens the sealed message from r on the first Read and serves
+// plaintext out of it.
+type reader struct {
+	gcm   cipher.AEAD
+	nonce []byte
+	r     io.Reader
+	plain []byte
+	off   int
+	ready bool
+}
+
+func (cr *reader) Read(p []byte) (int, error) {
+	if !cr.ready {
+		sealed, err := io.ReadAll(cr.r)
+		if err != nil {
+			return 0, err
+		}
+		plain, err := cr.gcm.Open(nil, cr.nonce, sealed, nil)
+		if err != nil {
+			return 0, err
+		}
+		cr.plain, cr.ready = plain, true
+	}
+	if cr.off >= len(cr.plain) {
+		return 0, io.EOF
+	}
+	n := copy(p, cr.plain[cr.off:])
+	cr.off += n
+	return n, nil
+}
+
+func (cr *reader) Close() error { return nil }
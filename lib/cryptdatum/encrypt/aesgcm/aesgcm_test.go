@@ -0,0 +1,63 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package aesgcm
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestRoundtrip(t *testing.T) {
+	key := bytes.Repeat([]byte{0x24}, 32)
+	plaintext := []byte("hello cryptdatum")
+
+	var ciphertext bytes.Buffer
+	ew, err := encrypt(key, nil, &ciphertext)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ew.Write(plaintext); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dr, err := decrypt(key, nil, bytes.NewReader(ciphertext.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := io.ReadAll(dr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypted payload does not match plaintext")
+	}
+}
+
+func TestEncryptGeneratesDistinctNoncePerCall(t *testing.T) {
+	key := bytes.Repeat([]byte{0x24}, 32)
+	plaintext := []byte("hello cryptdatum")
+
+	var first, second bytes.Buffer
+	for _, buf := range []*bytes.Buffer{&first, &second} {
+		ew, err := encrypt(key, nil, buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := ew.Write(plaintext); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := ew.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("expected two encryptions of the same plaintext under the same key to differ")
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+// Package ssec registers a Cryptdatum Encrypter/Decrypter pair implementing
+// an authenticated, SSE-C inspired encryption mode under ID. The caller
+// supplies a 32-byte master key; for each datum a random salt is generated
+// and used to derive a unique data key via HKDF-SHA256, along with a
+// key-MAC over that derived key. The salt and key-MAC travel as a preamble
+// on the ciphertext stream itself (the Encrypter/Decrypter interface has no
+// access to the Header, so there is no format break to reserve space for
+// them there), letting the Decrypter detect an incorrect key and return
+// ErrBadKey before it opens a single ciphertext segment.
+//
+// The payload is sealed with ChaCha20-Poly1305 as a sequence of
+// independently authenticated, length-prefixed segments, so it can be
+// decrypted — and have tampering detected, as ErrTampered — as a stream
+// without buffering the whole datum in memory.
+//
+// Importing this package for its side effect is enough to make ID usable
+// as an EncryptionAlg value:
+//
+//	import _ "howijd.network/lib/cryptdatum/encrypt/ssec"
+package ssec
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+
+	"howijd.network/lib/cryptdatum"
+)
+
+const (
+	// ID is the EncryptionAlg value datums encrypted with this package use.
+	ID uint16 = 2
+
+	saltSize    = 32
+	keyMACSize  = sha256.Size
+	segmentSize = 64 * 1024 // plaintext bytes sealed per segment
+)
+
+var (
+	// Err is the root of this package's sentinel errors.
+	Err = errors.New("ssec")
+
+	// ErrBadKey is returned by the Decrypter when the supplied key's
+	// derived key-MAC does not match the one stored for the datum, before
+	// any ciphertext segment is opened.
+	ErrBadKey = fmt.Errorf("%w: incorrect key", Err)
+
+	// ErrTampered is returned by the Decrypter when a sealed segment fails
+	// AEAD authentication.
+	ErrTampered = fmt.Errorf("%w: segment failed authentication", Err)
+)
+
+func init() {
+	cryptdatum.RegisterEncrypter(ID, encrypt)
+	cryptdatum.RegisterDecrypter(ID, decrypt)
+}
+
+// deriveKey derives the per-datum AEAD data key and its key-MAC from
+// masterKey and salt via HKDF-SHA256.
+func deriveKey(masterKey, salt []byte) (dataKey, keyMAC []byte, err error) {
+	h := hkdf.New(sha256.New, masterKey, salt, []byte("cryptdatum ssec data key"))
+	dataKey = make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(h, dataKey); err != nil {
+		return nil, nil, err
+	}
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write(salt)
+	mac.Write(dataKey)
+	return dataKey, mac.Sum(nil), nil
+}
+
+func encrypt(key, iv []byte, w io.Writer) (io.WriteCloser, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	dataKey, keyMAC, err := deriveKey(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(salt); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(keyMAC); err != nil {
+		return nil, err
+	}
+	return &writer{w: w, aead: aead}, nil
+}
+
+func decrypt(key, iv []byte, r io.Reader) (io.ReadCloser, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(r, salt); err != nil {
+		return nil, err
+	}
+	gotMAC := make([]byte, keyMACSize)
+	if _, err := io.ReadFull(r, gotMAC); err != nil {
+		return nil, err
+	}
+	dataKey, wantMAC, err := deriveKey(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(gotMAC, wantMAC) {
+		return nil, ErrBadKey
+	}
+	aead, err := chacha20poly1305.New(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{r: r, aead: aead}, nil
+}
+
+// writer seals plaintext written to it into fixed-size, independently
+// authenticated segments written to w: a 4-byte big-endian length prefix
+// followed by the sealed (ciphertext+tag) segment. Close seals any
+// remainder and appends a zero-length terminal segment.
+type writer struct {
+	w    io.Writer
+	aead cipher.AEAD
+	seq  uint64
+	buf  []byte
+}
+
+func (sw *writer) Write(p []byte) (int, error) {
+	sw.buf = append(sw.buf, p...)
+	for len(sw.buf) >= segmentSize {
+		if err := sw.sealSegment(sw.buf[:segmentSize]); err != nil {
+			return 0, err
+		}
+		sw.buf = sw.buf[segmentSize:]
+	}
+	return len(p), nil
+}
+
+func (sw *writer) Close() error {
+	if len(sw.buf) > 0 {
+		if err := sw.sealSegment(sw.buf); err != nil {
+			return err
+		}
+		sw.buf = nil
+	}
+	return sw.sealSegment(nil) // terminal zero-length segment
+}
+
+func (sw *writer) sealSegment(plain []byte) error {
+	sealed := sw.aead.Seal(nil, segmentNonce(sw.seq), plain, nil)
+	sw.seq++
+	var lenb [4]byte
+	binary.BigEndian.PutUint32(lenb[:], uint32(len(sealed)))
+	if _, err := sw.w.Write(lenb[:]); err != nil {
+		return err
+	}
+	_, err := sw.w.Write(sealed)
+	return err
+}
+
+// reader opens sealed segments from r one at a time as Read calls drain
+// the previous one, returning io.EOF once the terminal zero-length segment
+// has been consumed and ErrTampered if any segment fails authentication.
+type reader struct {
+	r    io.Reader
+	aead cipher.AEAD
+	seq  uint64
+	buf  []byte
+	off  int
+	done bool
+}
+
+func (sr *reader) Read(p []byte) (int, error) {
+	for !sr.done && sr.off >= len(sr.buf) {
+		seg, err := sr.nextSegment()
+		if err != nil {
+			if err == io.EOF {
+				// The stream ended before its terminal segment.
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		if len(seg) == 0 {
+			sr.done = true
+			break
+		}
+		sr.buf, sr.off = seg, 0
+	}
+	if sr.off >= len(sr.buf) {
+		return 0, io.EOF
+	}
+	n := copy(p, sr.buf[sr.off:])
+	sr.off += n
+	return n, nil
+}
+
+func (sr *reader) nextSegment() ([]byte, error) {
+	var lenb [4]byte
+	if _, err := io.ReadFull(sr.r, lenb[:]); err != nil {
+		return nil, err
+	}
+	sealed := make([]byte, binary.BigEndian.Uint32(lenb[:]))
+	if _, err := io.ReadFull(sr.r, sealed); err != nil {
+		return nil, err
+	}
+	plain, err := sr.aead.Open(nil, segmentNonce(sr.seq), sealed, nil)
+	sr.seq++
+	if err != nil {
+		return nil, ErrTampered
+	}
+	return plain, nil
+}
+
+func (sr *reader) Close() error { return nil }
+
+// segmentNonce derives the ChaCha20-Poly1305 nonce for segment seq from a
+// running per-datum counter, keeping nonces unique within a datum without
+// storing one per segment.
+func segmentNonce(seq uint64) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[chacha20poly1305.NonceSize-8:], seq)
+	return nonce
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package ssec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestRoundtrip(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	plaintext := bytes.Repeat([]byte("cryptdatum payload "), 10000) // spans multiple segments
+
+	var ciphertext bytes.Buffer
+	ew, err := encrypt(key, nil, &ciphertext)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ew.Write(plaintext); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dr, err := decrypt(key, nil, bytes.NewReader(ciphertext.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := io.ReadAll(dr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypted payload does not match plaintext")
+	}
+}
+
+func TestDecryptWithWrongKeyReturnsErrBadKey(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	wrongKey := bytes.Repeat([]byte{0x24}, 32)
+
+	var ciphertext bytes.Buffer
+	ew, err := encrypt(key, nil, &ciphertext)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ew.Write([]byte("secret")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = decrypt(wrongKey, nil, bytes.NewReader(ciphertext.Bytes()))
+	if !errors.Is(err, ErrBadKey) {
+		t.Errorf("expected ErrBadKey, got %v", err)
+	}
+}
+
+func TestDecryptTamperedSegmentReturnsErrTampered(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+
+	var ciphertext bytes.Buffer
+	ew, err := encrypt(key, nil, &ciphertext)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ew.Write([]byte("secret")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Flip a byte inside the first segment's sealed ciphertext (past the
+	// salt, key-MAC and length prefix), not the terminal zero-length
+	// segment, so the tampering is actually on the path the Read below
+	// exercises.
+	tampered := ciphertext.Bytes()
+	firstSegment := saltSize + keyMACSize + 4
+	tampered[firstSegment] ^= 0xFF
+
+	dr, err := decrypt(key, nil, bytes.NewReader(tampered))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = dr.Read(make([]byte, 6))
+	if !errors.Is(err, ErrTampered) {
+		t.Errorf("expected ErrTampered, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+// This file lives in an external cryptdatum_test package, rather than
+// cryptdatum itself like the package's other test files, because it needs
+// to import the compress/flate, encrypt/aesgcm and sign/ed25519
+// subpackages (to decode fixtures, and for their registered algorithm IDs)
+// — and those subpackages import cryptdatum to register their codecs,
+// which would be an import cycle from inside the cryptdatum package's own
+// test binary.
+package cryptdatum_test
+
+import (
+	"bytes"
+	stded25519 "crypto/ed25519"
+	"io"
+	"testing"
+	"time"
+
+	"howijd.network/lib/cryptdatum"
+	"howijd.network/lib/cryptdatum/compress/flate"
+	"howijd.network/lib/cryptdatum/encrypt/aesgcm"
+	"howijd.network/lib/cryptdatum/internal/obscuretestdata"
+	"howijd.network/lib/cryptdatum/sign/ed25519"
+)
+
+func readFixtureViaReader(t *testing.T, name string, opts ...cryptdatum.ReaderOption) ([]byte, cryptdatum.Header) {
+	t.Helper()
+	datum, err := obscuretestdata.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := cryptdatum.NewReader(bytes.NewReader(datum), opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return payload, r.Header
+}
+
+func TestDecodeCompressedFixture(t *testing.T) {
+	payload, header := readFixtureViaReader(t, "testdata/v1/compressed.cdt")
+	if header.Flags&cryptdatum.DatumCompressed == 0 {
+		t.Fatalf("expected fixture to have DatumCompressed set")
+	}
+
+	const want = "hello cryptdatum compressed fixture"
+	if string(payload) != want {
+		t.Errorf("expected %q, got %q", want, payload)
+	}
+}
+
+func TestDecodeEncryptedFixture(t *testing.T) {
+	key := bytes.Repeat([]byte{0x24}, 32)
+
+	payload, header := readFixtureViaReader(t, "testdata/v1/encrypted.cdt",
+		cryptdatum.WithDecryptionKey(key, nil))
+	if header.Flags&cryptdatum.DatumEncrypted == 0 {
+		t.Fatalf("expected fixture to have DatumEncrypted set")
+	}
+
+	const want = "hello cryptdatum encrypted fixture"
+	if string(payload) != want {
+		t.Errorf("expected %q, got %q", want, payload)
+	}
+}
+
+// TestWriterReaderRoundtripsCompressedEncryptedSigned exercises the real
+// flate/aesgcm/ed25519 subpackages together, rather than the
+// cryptdatum-internal fakes in reader_test.go, whose transforms (identity
+// compressor, byte-reversing "encrypter") compose to the same result
+// regardless of chain order and so cannot catch the chain being wired
+// backwards.
+func TestWriterReaderRoundtripsCompressedEncryptedSigned(t *testing.T) {
+	encKey := bytes.Repeat([]byte{0x24}, 32)
+	pub, priv, err := stded25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := bytes.Repeat([]byte("hello cryptdatum writer/reader roundtrip "), 100)
+	header := cryptdatum.Header{
+		Version:        cryptdatum.Version,
+		Flags:          cryptdatum.DatumCompressed | cryptdatum.DatumEncrypted | cryptdatum.DatumSigned,
+		Timestamp:      uint64(time.Date(2022, 5, 10, 4, 3, 2, 1, time.UTC).UnixNano()),
+		CompressionAlg: flate.ID,
+		EncryptionAlg:  aesgcm.ID,
+		SignatureType:  ed25519.ID,
+	}
+
+	var buf bytes.Buffer
+	w, err := cryptdatum.NewWriter(&buf, header,
+		cryptdatum.WithEncryptionKey(encKey, nil),
+		cryptdatum.WithSignatureKey(priv))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r, err := cryptdatum.NewReader(bytes.NewReader(buf.Bytes()),
+		cryptdatum.WithDecryptionKey(encKey, nil),
+		cryptdatum.WithVerificationKey(pub))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("roundtrip payload mismatch")
+	}
+}
+
+func TestDecodeSignedFixture(t *testing.T) {
+	pub, err := obscuretestdata.ReadFile("testdata/v1/signed.pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, header := readFixtureViaReader(t, "testdata/v1/signed.cdt",
+		cryptdatum.WithVerificationKey(pub))
+	if header.Flags&cryptdatum.DatumSigned == 0 {
+		t.Fatalf("expected fixture to have DatumSigned set")
+	}
+
+	const want = "hello cryptdatum signed fixture"
+	if string(payload) != want {
+		t.Errorf("expected %q, got %q", want, payload)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package cryptdatum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"howijd.network/lib/cryptdatum/internal/obscuretestdata"
+)
+
+// fuzzHeaderBytes renders a HeaderSize-byte header with the given version,
+// flags and timestamp, filling every other field with a placeholder value
+// so flag-dependent fields (e.g. Checksum, CompressionAlg, FileExt) are
+// always present regardless of which flags are set.
+func fuzzHeaderBytes(version uint16, flags, timestamp uint64) []byte {
+	b := make([]byte, HeaderSize)
+	copy(b[0:8], Magic[:])
+	binary.LittleEndian.PutUint16(b[8:10], version)
+	binary.LittleEndian.PutUint64(b[10:18], flags)
+	binary.LittleEndian.PutUint64(b[18:26], timestamp)
+	binary.LittleEndian.PutUint32(b[26:30], 1)
+	binary.LittleEndian.PutUint64(b[30:38], 0xC0FFEE)
+	binary.LittleEndian.PutUint64(b[38:46], HeaderSize)
+	binary.LittleEndian.PutUint16(b[46:48], 1)
+	binary.LittleEndian.PutUint16(b[48:50], 1)
+	binary.LittleEndian.PutUint16(b[50:52], 1)
+	binary.LittleEndian.PutUint32(b[52:56], 0)
+	copy(b[56:64], []byte("fileext."))
+	copy(b[64:72], []byte("customdt"))
+	copy(b[72:80], Delimiter[:])
+	return b
+}
+
+// seedHeaderCorpus adds the spec fixture plus a spread of draft-only
+// headers, every individual flag, boundary timestamps, an invalid version
+// and truncated slices to f's corpus.
+func seedHeaderCorpus(f *testing.F) {
+	if head, err := obscuretestdata.ReadFile("testdata/v1/has-aligned-header.cdt"); err == nil {
+		f.Add(head)
+	}
+
+	f.Add(fuzzHeaderBytes(Version, DatumDraft, 0))
+	f.Add(fuzzHeaderBytes(Version, DatumCompromised, 0))
+	f.Add(fuzzHeaderBytes(0, 0, magicDate))
+
+	allFlags := uint64(DatumInvalid | DatumDraft | DatumEmpty | DatumChecksum | DatumOPC |
+		DatumCompressed | DatumEncrypted | DatumExtractable | DatumSigned | DatumStreamable |
+		DatumCustom | DatumCompromised)
+	for _, flags := range []uint64{
+		0,
+		DatumInvalid,
+		DatumEmpty,
+		DatumChecksum,
+		DatumOPC,
+		DatumCompressed,
+		DatumEncrypted,
+		DatumExtractable,
+		DatumSigned,
+		DatumStreamable,
+		DatumCustom,
+		allFlags,
+	} {
+		f.Add(fuzzHeaderBytes(Version, flags, magicDate))
+	}
+
+	f.Add(fuzzHeaderBytes(Version, 0, magicDate-1))
+	f.Add(fuzzHeaderBytes(Version, 0, magicDate))
+
+	base := fuzzHeaderBytes(Version, allFlags, magicDate)
+	for n := 0; n < HeaderSize; n++ {
+		f.Add(base[:n])
+	}
+}
+
+func FuzzHasHeader(f *testing.F) {
+	seedHeaderCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		HasHeader(data) // must not panic regardless of input
+	})
+}
+
+func FuzzDecodeHeader(f *testing.F) {
+	seedHeaderCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		DecodeHeader(bytes.NewReader(data)) // must not panic regardless of input
+	})
+}
+
+// FuzzHasValidHeader checks the invariant tying HasValidHeader, DecodeHeader
+// and EncodeHeader together: whenever HasValidHeader(data) reports a valid
+// header, DecodeHeader must be able to decode it, and re-encoding the
+// decoded header via EncodeHeader must byte-equal the header's canonical,
+// normalized HeaderSize-byte form.
+func FuzzHasValidHeader(f *testing.F) {
+	seedHeaderCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if !HasValidHeader(data) {
+			return
+		}
+
+		header, err := DecodeHeader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("HasValidHeader(data) is true but DecodeHeader failed: %v", err)
+		}
+
+		var reencoded bytes.Buffer
+		if err := EncodeHeader(&reencoded, &header); err != nil {
+			t.Fatalf("HasValidHeader(data) is true but the decoded header failed to re-encode: %v", err)
+		}
+		if !bytes.Equal(reencoded.Bytes(), data[:HeaderSize]) {
+			t.Errorf("re-encoded header does not match the canonical form of the input:\nwant % x\ngot  % x", data[:HeaderSize], reencoded.Bytes())
+		}
+	})
+}
@@ -0,0 +1,49 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+// Package obscuretestdata stores binary test fixtures as base64 text files
+// so they survive being mirrored, proxied or synced by systems that mangle
+// raw binary content committed to a repo (see golang/go#34986, which
+// motivated archive/zip's package of the same name). Fixtures are named
+// name+".base64"; ReadFile and DecodeToTempFile decode them on demand.
+package obscuretestdata
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadFile base64-decodes the fixture stored at name+".base64" and returns
+// its raw bytes.
+func ReadFile(name string) ([]byte, error) {
+	encoded, err := os.ReadFile(name + ".base64")
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+}
+
+// DecodeToTempFile base64-decodes the fixture stored at name+".base64" into
+// a new temporary file and returns its path. The caller is responsible for
+// removing it.
+func DecodeToTempFile(name string) (string, error) {
+	data, err := ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", filepath.Base(name)+"-*")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package obscuretestdata
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFixture(t *testing.T, data []byte) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "fixture")
+	encoded := base64.StdEncoding.EncodeToString(data)
+	if err := os.WriteFile(name+".base64", []byte(encoded), 0640); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return name
+}
+
+func TestReadFile(t *testing.T) {
+	want := []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	name := writeFixture(t, want)
+
+	got, err := ReadFile(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("expected %x, got %x", want, got)
+	}
+}
+
+func TestDecodeToTempFile(t *testing.T) {
+	want := []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	name := writeFixture(t, want)
+
+	path, err := DecodeToTempFile(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(path)
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("expected %x, got %x", want, got)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package cryptdatum
+
+import (
+	"fmt"
+	"io"
+)
+
+// ErrStreamable is returned by NewReader when the decoded header has
+// DatumStreamable set; use NewStreamReader for those datums instead.
+var ErrStreamable = fmt.Errorf("%w: DatumStreamable is set, use NewStreamReader", Err)
+
+// ReaderOption configures a Reader's handling of algorithms that need key
+// material the Header itself does not carry.
+type ReaderOption func(*Reader)
+
+// WithDecryptionKey supplies the key and IV a Decrypter registered for
+// Header.EncryptionAlg needs when DatumEncrypted is set.
+func WithDecryptionKey(key, iv []byte) ReaderOption {
+	return func(cr *Reader) {
+		cr.decKey, cr.decIV = key, iv
+	}
+}
+
+// WithVerificationKey supplies the key a Verifier registered for
+// Header.SignatureType needs when DatumSigned is set.
+func WithVerificationKey(key []byte) ReaderOption {
+	return func(cr *Reader) {
+		cr.verifyKey = key
+	}
+}
+
+// Reader reads a non-streaming Cryptdatum stream: a header followed by its
+// payload, routed back through the Decompressor, Decrypter and Verifier
+// registered for the header's CompressionAlg, EncryptionAlg and
+// SignatureType, reversing the compressed-then-encrypted-then-signed chain
+// Writer produces.
+type Reader struct {
+	Header    Header
+	src       io.Reader
+	chain     []io.Closer
+	decKey    []byte
+	decIV     []byte
+	verifyKey []byte
+}
+
+// NewReader decodes the header from r and returns a Reader for the payload
+// that follows, chaining it through any registered decompressor/decrypter/
+// verifier the header's flags call for. It returns ErrStreamable if the
+// header has DatumStreamable set.
+func NewReader(r io.Reader, opts ...ReaderOption) (*Reader, error) {
+	header, err := DecodeHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if header.Flags&DatumStreamable != 0 {
+		return nil, ErrStreamable
+	}
+
+	cr := &Reader{Header: header}
+	for _, opt := range opts {
+		opt(cr)
+	}
+
+	// Writer produces compress(plaintext) -> encrypt -> sign -> wire, so
+	// reversing it means unwrapping in the opposite order: the verifier
+	// reads the raw wire bytes first, then the decrypter, then the
+	// decompressor last, to hand cr.src plaintext.
+	var src io.Reader = r
+	if header.Flags&DatumSigned != 0 {
+		fn, ok := verifierFor(header.SignatureType)
+		if !ok {
+			return nil, ErrUnknownSignatureType
+		}
+		rc, err := fn(cr.verifyKey, src)
+		if err != nil {
+			return nil, err
+		}
+		cr.chain = append(cr.chain, rc)
+		src = rc
+	}
+	if header.Flags&DatumEncrypted != 0 {
+		fn, ok := decrypterFor(header.EncryptionAlg)
+		if !ok {
+			return nil, ErrUnknownEncryptionAlg
+		}
+		rc, err := fn(cr.decKey, cr.decIV, src)
+		if err != nil {
+			return nil, err
+		}
+		cr.chain = append(cr.chain, rc)
+		src = rc
+	}
+	if header.Flags&DatumCompressed != 0 {
+		fn, ok := decompressorFor(header.CompressionAlg)
+		if !ok {
+			return nil, ErrUnknownCompressionAlg
+		}
+		rc := fn(src)
+		cr.chain = append(cr.chain, rc)
+		src = rc
+	}
+	cr.src = src
+	return cr, nil
+}
+
+// Read returns decompressed/decrypted/verified payload bytes.
+func (cr *Reader) Read(p []byte) (int, error) {
+	return cr.src.Read(p)
+}
+
+// Close closes the decompressor/decrypter/verifier chain outermost first:
+// the decompressor, then the decrypter, then the verifier.
+func (cr *Reader) Close() error {
+	for i := len(cr.chain) - 1; i >= 0; i-- {
+		if err := cr.chain[i].Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package cryptdatum
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// Fake compressor/encrypter/signer registered under the cryptdatum package
+// test binary to exercise Reader's chaining without pulling in the real
+// flate/aesgcm/ed25519 subpackages, which would import this package back
+// and create an import cycle from an internal test file.
+
+const (
+	fakeCompressionAlg uint16 = 0xFFF3
+	fakeEncryptionAlg  uint16 = 0xFFF3
+	fakeSignatureType  uint16 = 0xFFF3
+)
+
+func init() {
+	RegisterCompressor(fakeCompressionAlg, func(w io.Writer) io.WriteCloser {
+		return nopWriteCloser{w}
+	})
+	RegisterDecompressor(fakeCompressionAlg, func(r io.Reader) io.ReadCloser {
+		return io.NopCloser(r)
+	})
+	RegisterEncrypter(fakeEncryptionAlg, func(key, iv []byte, w io.Writer) (io.WriteCloser, error) {
+		return &reverseWriter{w: w}, nil
+	})
+	RegisterDecrypter(fakeEncryptionAlg, func(key, iv []byte, r io.Reader) (io.ReadCloser, error) {
+		return &reverseReader{r: r}, nil
+	})
+	RegisterSigner(fakeSignatureType, func(key []byte, w io.Writer) (io.WriteCloser, error) {
+		return &trailerWriter{w: w, trailer: []byte("SIGNATURE")}, nil
+	})
+	RegisterVerifier(fakeSignatureType, func(key []byte, r io.Reader) (io.ReadCloser, error) {
+		return &trailerReader{r: r, trailer: []byte("SIGNATURE")}, nil
+	})
+}
+
+// reverseWriter buffers writes and, on Close, writes the reverse of the
+// buffered bytes to w: a toy stand-in for an encryption transform.
+type reverseWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (rw *reverseWriter) Write(p []byte) (int, error) {
+	rw.buf = append(rw.buf, p...)
+	return len(p), nil
+}
+
+func (rw *reverseWriter) Close() error {
+	_, err := rw.w.Write(reverseBytes(rw.buf))
+	return err
+}
+
+// reverseReader reads all of r on the first Read and serves it reversed.
+type reverseReader struct {
+	r     io.Reader
+	plain []byte
+	off   int
+	ready bool
+}
+
+func (rr *reverseReader) Read(p []byte) (int, error) {
+	if !rr.ready {
+		all, err := io.ReadAll(rr.r)
+		if err != nil {
+			return 0, err
+		}
+		rr.plain, rr.ready = reverseBytes(all), true
+	}
+	if rr.off >= len(rr.plain) {
+		return 0, io.EOF
+	}
+	n := copy(p, rr.plain[rr.off:])
+	rr.off += n
+	return n, nil
+}
+
+func (rr *reverseReader) Close() error { return nil }
+
+func reverseBytes(p []byte) []byte {
+	out := make([]byte, len(p))
+	for i, b := range p {
+		out[len(p)-1-i] = b
+	}
+	return out
+}
+
+// trailerWriter buffers writes and appends trailer to w on Close.
+type trailerWriter struct {
+	w       io.Writer
+	trailer []byte
+	buf     []byte
+}
+
+func (tw *trailerWriter) Write(p []byte) (int, error) {
+	tw.buf = append(tw.buf, p...)
+	return len(p), nil
+}
+
+func (tw *trailerWriter) Close() error {
+	if _, err := tw.w.Write(tw.buf); err != nil {
+		return err
+	}
+	_, err := tw.w.Write(tw.trailer)
+	return err
+}
+
+// trailerReader reads all of r on the first Read, verifies the trailing
+// bytes against trailer and serves what precedes it.
+type trailerReader struct {
+	r       io.Reader
+	trailer []byte
+	data    []byte
+	off     int
+	ready   bool
+}
+
+var errBadTrailer = errors.New("reader_test: trailer mismatch")
+
+func (tr *trailerReader) Read(p []byte) (int, error) {
+	if !tr.ready {
+		all, err := io.ReadAll(tr.r)
+		if err != nil {
+			return 0, err
+		}
+		n := len(all) - len(tr.trailer)
+		if n < 0 || !bytes.Equal(all[n:], tr.trailer) {
+			return 0, errBadTrailer
+		}
+		tr.data, tr.ready = all[:n], true
+	}
+	if tr.off >= len(tr.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, tr.data[tr.off:])
+	tr.off += n
+	return n, nil
+}
+
+func (tr *trailerReader) Close() error { return nil }
+
+func TestReaderRoundtripsCompressedEncryptedSigned(t *testing.T) {
+	payload := []byte("hello cryptdatum reader")
+	header := Header{
+		Version:        Version,
+		Flags:          DatumCompressed | DatumEncrypted | DatumSigned,
+		Timestamp:      magicDate,
+		CompressionAlg: fakeCompressionAlg,
+		EncryptionAlg:  fakeEncryptionAlg,
+		SignatureType:  fakeSignatureType,
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("expected %q, got %q", payload, got)
+	}
+}
+
+func TestNewReaderRejectsStreamable(t *testing.T) {
+	header := Header{Version: Version, Flags: DatumStreamable, Timestamp: magicDate}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := NewReader(bytes.NewReader(buf.Bytes())); !errors.Is(err, ErrStreamable) {
+		t.Errorf("expected ErrStreamable, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package cryptdatum
+
+import (
+	"io"
+	"sync"
+)
+
+// Compressor returns an io.WriteCloser that compresses bytes written to it
+// through to w, using the algorithm registered for a CompressionAlg id.
+type Compressor func(w io.Writer) io.WriteCloser
+
+// Decompressor returns an io.ReadCloser that decompresses bytes read from
+// r, using the algorithm registered for a CompressionAlg id.
+type Decompressor func(r io.Reader) io.ReadCloser
+
+// Encrypter returns an io.WriteCloser that encrypts bytes written to it
+// through to w with key and iv, using the algorithm registered for an
+// EncryptionAlg id.
+type Encrypter func(key, iv []byte, w io.Writer) (io.WriteCloser, error)
+
+// Decrypter returns an io.ReadCloser that decrypts bytes read from r with
+// key and iv, using the algorithm registered for an EncryptionAlg id.
+type Decrypter func(key, iv []byte, r io.Reader) (io.ReadCloser, error)
+
+// Signer returns an io.WriteCloser that passes bytes written to it through
+// to w and, once closed, appends a signature over them computed with key,
+// using the algorithm registered for a SignatureType id.
+type Signer func(key []byte, w io.Writer) (io.WriteCloser, error)
+
+// Verifier returns an io.ReadCloser that reads the signed stream produced
+// by the matching Signer from r, verifying the trailing signature against
+// key before any payload byte is returned, using the algorithm registered
+// for a SignatureType id.
+type Verifier func(key []byte, r io.Reader) (io.ReadCloser, error)
+
+// This mirrors the registration pattern archive/zip uses in its
+// register.go: callers register algorithm implementations by id, and the
+// Writer/Reader look them up based on the header fields they are given.
+var (
+	regmu         sync.RWMutex
+	compressors   = map[uint16]Compressor{}
+	decompressors = map[uint16]Decompressor{}
+	encrypters    = map[uint16]Encrypter{}
+	decrypters    = map[uint16]Decrypter{}
+	signers       = map[uint16]Signer{}
+	verifiers     = map[uint16]Verifier{}
+)
+
+// RegisterCompressor registers fn as the Compressor for CompressionAlg id.
+// Registering the same id twice panics.
+func RegisterCompressor(id uint16, fn Compressor) {
+	regmu.Lock()
+	defer regmu.Unlock()
+	if _, dup := compressors[id]; dup {
+		panic("cryptdatum: compressor already registered for id")
+	}
+	compressors[id] = fn
+}
+
+// RegisterDecompressor registers fn as the Decompressor for CompressionAlg
+// id. Registering the same id twice panics.
+func RegisterDecompressor(id uint16, fn Decompressor) {
+	regmu.Lock()
+	defer regmu.Unlock()
+	if _, dup := decompressors[id]; dup {
+		panic("cryptdatum: decompressor already registered for id")
+	}
+	decompressors[id] = fn
+}
+
+// RegisterEncrypter registers fn as the Encrypter for EncryptionAlg id.
+// Registering the same id twice panics.
+func RegisterEncrypter(id uint16, fn Encrypter) {
+	regmu.Lock()
+	defer regmu.Unlock()
+	if _, dup := encrypters[id]; dup {
+		panic("cryptdatum: encrypter already registered for id")
+	}
+	encrypters[id] = fn
+}
+
+// RegisterDecrypter registers fn as the Decrypter for EncryptionAlg id.
+// Registering the same id twice panics.
+func RegisterDecrypter(id uint16, fn Decrypter) {
+	regmu.Lock()
+	defer regmu.Unlock()
+	if _, dup := decrypters[id]; dup {
+		panic("cryptdatum: decrypter already registered for id")
+	}
+	decrypters[id] = fn
+}
+
+// RegisterSigner registers fn as the Signer for SignatureType id.
+// Registering the same id twice panics.
+func RegisterSigner(id uint16, fn Signer) {
+	regmu.Lock()
+	defer regmu.Unlock()
+	if _, dup := signers[id]; dup {
+		panic("cryptdatum: signer already registered for id")
+	}
+	signers[id] = fn
+}
+
+// RegisterVerifier registers fn as the Verifier for SignatureType id.
+// Registering the same id twice panics.
+func RegisterVerifier(id uint16, fn Verifier) {
+	regmu.Lock()
+	defer regmu.Unlock()
+	if _, dup := verifiers[id]; dup {
+		panic("cryptdatum: verifier already registered for id")
+	}
+	verifiers[id] = fn
+}
+
+func compressorFor(id uint16) (Compressor, bool) {
+	regmu.RLock()
+	defer regmu.RUnlock()
+	fn, ok := compressors[id]
+	return fn, ok
+}
+
+func decompressorFor(id uint16) (Decompressor, bool) {
+	regmu.RLock()
+	defer regmu.RUnlock()
+	fn, ok := decompressors[id]
+	return fn, ok
+}
+
+func encrypterFor(id uint16) (Encrypter, bool) {
+	regmu.RLock()
+	defer regmu.RUnlock()
+	fn, ok := encrypters[id]
+	return fn, ok
+}
+
+func decrypterFor(id uint16) (Decrypter, bool) {
+	regmu.RLock()
+	defer regmu.RUnlock()
+	fn, ok := decrypters[id]
+	return fn, ok
+}
+
+func signerFor(id uint16) (Signer, bool) {
+	regmu.RLock()
+	defer regmu.RUnlock()
+	fn, ok := signers[id]
+	return fn, ok
+}
+
+func verifierFor(id uint16) (Verifier, bool) {
+	regmu.RLock()
+	defer regmu.RUnlock()
+	fn, ok := verifiers[id]
+	return fn, ok
+}
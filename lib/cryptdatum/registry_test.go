@@ -0,0 +1,66 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package cryptdatum
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestRegisterCompressorRoundtrip(t *testing.T) {
+	const id uint16 = 0xFFF1
+
+	RegisterCompressor(id, func(w io.Writer) io.WriteCloser {
+		return nopWriteCloser{w}
+	})
+	RegisterDecompressor(id, func(r io.Reader) io.ReadCloser {
+		return io.NopCloser(r)
+	})
+
+	fn, ok := compressorFor(id)
+	if !ok {
+		t.Fatalf("expected compressor registered for id %d", id)
+	}
+	var buf bytes.Buffer
+	wc := fn(&buf)
+	if _, err := wc.Write([]byte("payload")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := wc.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "payload" {
+		t.Errorf("expected %q, got %q", "payload", buf.String())
+	}
+
+	dfn, ok := decompressorFor(id)
+	if !ok {
+		t.Fatalf("expected decompressor registered for id %d", id)
+	}
+	got, err := io.ReadAll(dfn(&buf))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != "payload" {
+		t.Errorf("expected %q, got %q", "payload", got)
+	}
+}
+
+func TestRegisterCompressorPanicsOnDuplicate(t *testing.T) {
+	const id uint16 = 0xFFF2
+	RegisterCompressor(id, func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} })
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic on duplicate registration")
+		}
+	}()
+	RegisterCompressor(id, func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} })
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
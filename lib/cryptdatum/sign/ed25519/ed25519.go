@@ -0,0 +1,102 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+// Package ed25519 registers the Cryptdatum Signer/Verifier pair for
+// Ed25519 signatures, backed by the standard library's crypto/ed25519,
+// under ID. Importing this package for its side effect is enough to make
+// ID usable as a SignatureType value:
+//
+//	import _ "howijd.network/lib/cryptdatum/sign/ed25519"
+//
+// The signature is appended after the payload it covers, so the full
+// payload must fit in memory; datums too large for that should set
+// DatumStreamable instead.
+package ed25519
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"io"
+
+	"howijd.network/lib/cryptdatum"
+)
+
+// ID is the SignatureType value datums signed with this package use.
+const ID uint16 = 1
+
+func init() {
+	cryptdatum.RegisterSigner(ID, sign)
+	cryptdatum.RegisterVerifier(ID, verify)
+}
+
+func sign(key []byte, w io.Writer) (io.WriteCloser, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("ed25519: invalid private key size")
+	}
+	return &writer{key: ed25519.PrivateKey(key), w: w}, nil
+}
+
+func verify(key []byte, r io.Reader) (io.ReadCloser, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("ed25519: invalid public key size")
+	}
+	return &reader{key: ed25519.PublicKey(key), r: r}, nil
+}
+
+// writer buffers the payload written to it and appends its Ed25519
+// signature to w on Close.
+type writer struct {
+	key ed25519.PrivateKey
+	w   io.Writer
+	buf []byte
+}
+
+func (sw *writer) Write(p []byte) (int, error) {
+	sw.buf = append(sw.buf, p...)
+	return len(p), nil
+}
+
+func (sw *writer) Close() error {
+	if _, err := sw.w.Write(sw.buf); err != nil {
+		return err
+	}
+	_, err := sw.w.Write(ed25519.Sign(sw.key, sw.buf))
+	return err
+}
+
+// reader reads the full signed stream from r on the first Read, verifies
+// the trailing signature and serves the payload that precedes it.
+type reader struct {
+	key   ed25519.PublicKey
+	r     io.Reader
+	data  []byte
+	off   int
+	ready bool
+}
+
+func (vr *reader) Read(p []byte) (int, error) {
+	if !vr.ready {
+		all, err := io.ReadAll(vr.r)
+		if err != nil {
+			return 0, err
+		}
+		if len(all) < ed25519.SignatureSize {
+			return 0, errors.New("ed25519: truncated signed stream")
+		}
+		n := len(all) - ed25519.SignatureSize
+		data, sig := all[:n], all[n:]
+		if !ed25519.Verify(vr.key, data, sig) {
+			return 0, errors.New("ed25519: signature verification failed")
+		}
+		vr.data, vr.ready = data, true
+	}
+	if vr.off >= len(vr.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, vr.data[vr.off:])
+	vr.off += n
+	return n, nil
+}
+
+func (vr *reader) Close() error { return nil }
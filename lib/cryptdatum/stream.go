@@ -0,0 +1,312 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package cryptdatum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/crc64"
+	"io"
+)
+
+var (
+	// ErrNotStreamable is returned by NewStreamReader when the decoded
+	// header does not have DatumStreamable set.
+	ErrNotStreamable = fmt.Errorf("%w: DatumStreamable is not set", Err)
+
+	// ErrSegmentChecksumMismatch is returned when a stream segment's CRC64
+	// trailer does not match its contents.
+	ErrSegmentChecksumMismatch = fmt.Errorf("%w: segment checksum mismatch", Err)
+)
+
+// StreamSegmentSize is the number of plaintext bytes Writer buffers before
+// emitting a stream segment for a DatumStreamable datum.
+const StreamSegmentSize = 64 * 1024
+
+// A stream segment is framed as a 4-byte big-endian length, that many
+// segment bytes (independently compressed/encrypted, per the header's
+// CompressionAlg/EncryptionAlg), and an 8-byte big-endian CRC64 trailer
+// over those bytes. A zero-length segment marks the end of the stream. If
+// DatumSigned is set, one more frame follows the terminal segment: the
+// signature computed, via the Signer registered for the header's
+// SignatureType, over the concatenated SHA-256 hashes of every preceding
+// segment (pre-terminal, post-compression/encryption) — letting a reader
+// verify the whole sequence without re-buffering every segment.
+//
+// Signer/Verifier implementations registered with this package are
+// expected to write their input through unchanged and append the signature
+// on Close (as the bundled sign/ed25519 package does); Writer.closeStream
+// and StreamReader.verifySignature rely on that convention to isolate the
+// signature bytes from the hash-chain bytes they cover.
+
+func writeFrame(w io.Writer, seg []byte) error {
+	var lenb [4]byte
+	binary.BigEndian.PutUint32(lenb[:], uint32(len(seg)))
+	if _, err := w.Write(lenb[:]); err != nil {
+		return err
+	}
+	if len(seg) > 0 {
+		if _, err := w.Write(seg); err != nil {
+			return err
+		}
+	}
+	var trail [8]byte
+	binary.BigEndian.PutUint64(trail[:], crc64.Checksum(seg, crc64Table))
+	_, err := w.Write(trail[:])
+	return err
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	var lenb [4]byte
+	if _, err := io.ReadFull(r, lenb[:]); err != nil {
+		return nil, err
+	}
+	seg := make([]byte, binary.BigEndian.Uint32(lenb[:]))
+	if len(seg) > 0 {
+		if _, err := io.ReadFull(r, seg); err != nil {
+			return nil, err
+		}
+	}
+	var trail [8]byte
+	if _, err := io.ReadFull(r, trail[:]); err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint64(trail[:]) != crc64.Checksum(seg, crc64Table) {
+		return nil, ErrSegmentChecksumMismatch
+	}
+	return seg, nil
+}
+
+func concatHashes(hashes [][sha256.Size]byte) []byte {
+	out := make([]byte, 0, len(hashes)*sha256.Size)
+	for _, h := range hashes {
+		out = append(out, h[:]...)
+	}
+	return out
+}
+
+// writeSegments buffers p and emits StreamSegmentSize-sized stream segments
+// as the buffer fills. It is Writer's Write in streaming mode.
+func (cw *Writer) writeSegments(p []byte) (int, error) {
+	cw.segBuf = append(cw.segBuf, p...)
+	for len(cw.segBuf) >= StreamSegmentSize {
+		if err := cw.emitSegment(cw.segBuf[:StreamSegmentSize]); err != nil {
+			return 0, err
+		}
+		cw.segBuf = cw.segBuf[StreamSegmentSize:]
+	}
+	return len(p), nil
+}
+
+// emitSegment compresses and/or encrypts plain, per the header's flags,
+// frames the result to the sink, and records its hash for a later signature.
+func (cw *Writer) emitSegment(plain []byte) error {
+	seg := plain
+	if cw.header.Flags&DatumCompressed != 0 {
+		fn, ok := compressorFor(cw.header.CompressionAlg)
+		if !ok {
+			return ErrUnknownCompressionAlg
+		}
+		var buf bytes.Buffer
+		wc := fn(&buf)
+		if _, err := wc.Write(seg); err != nil {
+			return err
+		}
+		if err := wc.Close(); err != nil {
+			return err
+		}
+		seg = buf.Bytes()
+	}
+	if cw.header.Flags&DatumEncrypted != 0 {
+		fn, ok := encrypterFor(cw.header.EncryptionAlg)
+		if !ok {
+			return ErrUnknownEncryptionAlg
+		}
+		var buf bytes.Buffer
+		wc, err := fn(cw.encKey, cw.encIV, &buf)
+		if err != nil {
+			return err
+		}
+		if _, err := wc.Write(seg); err != nil {
+			return err
+		}
+		if err := wc.Close(); err != nil {
+			return err
+		}
+		seg = buf.Bytes()
+	}
+	cw.segHashes = append(cw.segHashes, sha256.Sum256(seg))
+	return writeFrame(cw.sink, seg)
+}
+
+// closeStream flushes any buffered remainder as a final segment, writes
+// the terminal zero-length segment and, when DatumSigned is set, the
+// signature frame over the segment hash chain.
+func (cw *Writer) closeStream() error {
+	if len(cw.segBuf) > 0 {
+		if err := cw.emitSegment(cw.segBuf); err != nil {
+			return err
+		}
+		cw.segBuf = nil
+	}
+	if err := writeFrame(cw.sink, nil); err != nil {
+		return err
+	}
+	if cw.header.Flags&DatumSigned == 0 {
+		return nil
+	}
+
+	fn, ok := signerFor(cw.header.SignatureType)
+	if !ok {
+		return ErrUnknownSignatureType
+	}
+	hashes := concatHashes(cw.segHashes)
+	var buf bytes.Buffer
+	wc, err := fn(cw.sigKey, &buf)
+	if err != nil {
+		return err
+	}
+	if _, err := wc.Write(hashes); err != nil {
+		return err
+	}
+	if err := wc.Close(); err != nil {
+		return err
+	}
+	return writeFrame(cw.sink, buf.Bytes()[len(hashes):])
+}
+
+// StreamReaderOption configures a StreamReader's handling of algorithms
+// that need key material the Header itself does not carry.
+type StreamReaderOption func(*StreamReader)
+
+// WithStreamDecryptionKey supplies the key and IV a Decrypter registered
+// for Header.EncryptionAlg needs when DatumEncrypted is set.
+func WithStreamDecryptionKey(key, iv []byte) StreamReaderOption {
+	return func(sr *StreamReader) {
+		sr.decKey, sr.decIV = key, iv
+	}
+}
+
+// WithStreamVerificationKey supplies the key a Verifier registered for
+// Header.SignatureType needs when DatumSigned is set.
+func WithStreamVerificationKey(key []byte) StreamReaderOption {
+	return func(sr *StreamReader) {
+		sr.verifyKey = key
+	}
+}
+
+// StreamReader reads a DatumStreamable Cryptdatum stream segment by
+// segment, so a consumer can validate and process each one before the
+// whole datum has arrived.
+type StreamReader struct {
+	r         io.Reader
+	Header    Header
+	decKey    []byte
+	decIV     []byte
+	verifyKey []byte
+	hashes    [][sha256.Size]byte
+	done      bool
+}
+
+// NewStreamReader decodes the header from r and returns a StreamReader for
+// the segments that follow. It returns ErrNotStreamable if the header does
+// not have DatumStreamable set.
+func NewStreamReader(r io.Reader, opts ...StreamReaderOption) (*StreamReader, error) {
+	header, err := DecodeHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if header.Flags&DatumStreamable == 0 {
+		return nil, ErrNotStreamable
+	}
+	sr := &StreamReader{r: r, Header: header}
+	for _, opt := range opts {
+		opt(sr)
+	}
+	return sr, nil
+}
+
+// NextSegment returns the next decompressed/decrypted payload segment. It
+// returns io.EOF once the terminal zero-length segment has been consumed —
+// after verifying the trailing signature frame, when DatumSigned is set.
+func (sr *StreamReader) NextSegment() ([]byte, error) {
+	if sr.done {
+		return nil, io.EOF
+	}
+
+	seg, err := readFrame(sr.r)
+	if err != nil {
+		if err == io.EOF {
+			// The stream ended before its terminal segment.
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if len(seg) == 0 {
+		sr.done = true
+		if sr.Header.Flags&DatumSigned != 0 {
+			if err := sr.verifySignature(); err != nil {
+				return nil, err
+			}
+		}
+		return nil, io.EOF
+	}
+	sr.hashes = append(sr.hashes, sha256.Sum256(seg))
+
+	plain := seg
+	if sr.Header.Flags&DatumEncrypted != 0 {
+		fn, ok := decrypterFor(sr.Header.EncryptionAlg)
+		if !ok {
+			return nil, ErrUnknownEncryptionAlg
+		}
+		rc, err := fn(sr.decKey, sr.decIV, bytes.NewReader(plain))
+		if err != nil {
+			return nil, err
+		}
+		plain, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if sr.Header.Flags&DatumCompressed != 0 {
+		fn, ok := decompressorFor(sr.Header.CompressionAlg)
+		if !ok {
+			return nil, ErrUnknownCompressionAlg
+		}
+		rc := fn(bytes.NewReader(plain))
+		out, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		plain = out
+	}
+	return plain, nil
+}
+
+// verifySignature reads the trailing signature frame and verifies it,
+// via the Verifier registered for Header.SignatureType, against the
+// concatenated SHA-256 hashes of the segments already read.
+func (sr *StreamReader) verifySignature() error {
+	sigFrame, err := readFrame(sr.r)
+	if err != nil {
+		return err
+	}
+	fn, ok := verifierFor(sr.Header.SignatureType)
+	if !ok {
+		return ErrUnknownSignatureType
+	}
+	hashes := concatHashes(sr.hashes)
+	rc, err := fn(sr.verifyKey, io.MultiReader(bytes.NewReader(hashes), bytes.NewReader(sigFrame)))
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.ReadAll(rc)
+	return err
+}
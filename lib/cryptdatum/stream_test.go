@@ -0,0 +1,92 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package cryptdatum
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestStreamRoundtrip(t *testing.T) {
+	header := Header{Version: Version, Flags: DatumStreamable, Timestamp: magicDate}
+	payload := bytes.Repeat([]byte("cryptdatum stream segment "), 10000) // spans multiple segments
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sr, err := NewStreamReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []byte
+	for {
+		seg, err := sr.NextSegment()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, seg...)
+	}
+
+	if !bytes.Equal(got, payload) {
+		t.Errorf("reassembled payload does not match the original")
+	}
+
+	if _, err := sr.NextSegment(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF after the terminal segment, got %v", err)
+	}
+}
+
+func TestNewStreamReaderRequiresDatumStreamable(t *testing.T) {
+	header := Header{Version: Version, Timestamp: magicDate}
+	var buf bytes.Buffer
+	if err := EncodeHeader(&buf, &header); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := NewStreamReader(&buf); !errors.Is(err, ErrNotStreamable) {
+		t.Errorf("expected ErrNotStreamable, got %v", err)
+	}
+}
+
+func TestStreamSegmentChecksumMismatch(t *testing.T) {
+	header := Header{Version: Version, Flags: DatumStreamable, Timestamp: magicDate}
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := w.Write([]byte("segment")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	corrupted := buf.Bytes()
+	corrupted[HeaderSize+4] ^= 0xFF // flip a byte inside the first segment
+
+	sr, err := NewStreamReader(bytes.NewReader(corrupted))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := sr.NextSegment(); !errors.Is(err, ErrSegmentChecksumMismatch) {
+		t.Errorf("expected ErrSegmentChecksumMismatch, got %v", err)
+	}
+}
@@ -5,22 +5,31 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/binary"
 	"os"
 	"time"
 
 	"golang.org/x/exp/slog"
-)
-
-var (
-	magic = [8]byte{0xA7, 0xF6, 0xE5, 0xD4, 0xC3, 0xB2, 0xA1, 0xE1}
 
-	delimiter = [8]byte{0xC8, 0xB7, 0xA6, 0xE5, 0xD4, 0xC3, 0xB2, 0xF1}
+	"howijd.network/lib/cryptdatum"
+	_ "howijd.network/lib/cryptdatum/compress/flate"
+	_ "howijd.network/lib/cryptdatum/encrypt/aesgcm"
+	_ "howijd.network/lib/cryptdatum/sign/ed25519"
 )
 
 func main() {
 	var generators = []func() (string, error){
 		createTestHasValidHeader,
+		createSparse,
+		createTruncated,
+		createTampered,
+		createCompressed,
+		createEncrypted,
+		createSigned,
 	}
 	for _, gen := range generators {
 		if name, err := gen(); err != nil {
@@ -30,65 +39,210 @@ func main() {
 	}
 }
 
+// writeFixture base64-encodes data and writes it to name+".base64", so the
+// fixture survives being committed and mirrored as text. See
+// internal/obscuretestdata, which decodes fixtures written this way.
+func writeFixture(name string, data []byte) (string, error) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	return name, os.WriteFile(name+".base64", []byte(encoded), 0640)
+}
+
 // specV1createValidForTesting outputs empty cdt for testing
 // header field alignment.
 func createTestHasValidHeader() (string, error) {
 	const name = "has-aligned-header.cdt"
-	var header [80]byte
 
-	// Set Magic
-	copy(header[0:8], magic[:])
+	ts := time.Date(2022, 5, 10, 4, 3, 2, 1, time.UTC).UnixNano()
 
-	// Set version, must be 1
-	binary.LittleEndian.PutUint16(header[8:10], 1)
+	header := cryptdatum.Header{
+		Version: cryptdatum.Version,
+		Flags: cryptdatum.DatumEmpty | cryptdatum.DatumChecksum | cryptdatum.DatumOPC |
+			cryptdatum.DatumEncrypted | cryptdatum.DatumCompressed | cryptdatum.DatumSigned |
+			cryptdatum.DatumCustom | cryptdatum.DatumExtractable,
+		Timestamp:      uint64(ts),
+		OPC:            2,
+		Checksum:       binary.LittleEndian.Uint64([]byte{'c', 'h', 'e', 'c', 'k', 's', 'u', 'm'}),
+		Size:           3,
+		CompressionAlg: 4,
+		EncryptionAlg:  5,
+		SignatureType:  6,
+		SignatureSize:  7,
+		FileExt:        "affixing",
+		Custom:         [8]byte{'t', 'a', 'i', 'l', 'o', 'r', 'e', 'd'},
+	}
 
-	ts := time.Date(2022, 5, 10, 4, 3, 2, 1, time.UTC).UnixNano()
+	var buf bytes.Buffer
+	if err := cryptdatum.EncodeHeader(&buf, &header); err != nil {
+		return name, err
+	}
+
+	return writeFixture(name, buf.Bytes())
+}
+
+// createSparse outputs a header-only datum (DatumEmpty, no payload) for
+// testing the empty-payload path.
+func createSparse() (string, error) {
+	const name = "sparse.cdt"
+
+	header := cryptdatum.Header{
+		Version:   cryptdatum.Version,
+		Flags:     cryptdatum.DatumEmpty,
+		Timestamp: uint64(time.Date(2022, 5, 10, 4, 3, 2, 1, time.UTC).UnixNano()),
+		Size:      cryptdatum.HeaderSize,
+	}
+
+	var buf bytes.Buffer
+	if err := cryptdatum.EncodeHeader(&buf, &header); err != nil {
+		return name, err
+	}
+
+	return writeFixture(name, buf.Bytes())
+}
+
+// createTruncated outputs the first half of an otherwise valid header, for
+// testing that a short read is rejected rather than read out of bounds.
+func createTruncated() (string, error) {
+	const name = "truncated.cdt"
+
+	header := cryptdatum.Header{
+		Version:   cryptdatum.Version,
+		Flags:     cryptdatum.DatumEmpty,
+		Timestamp: uint64(time.Date(2022, 5, 10, 4, 3, 2, 1, time.UTC).UnixNano()),
+		Size:      cryptdatum.HeaderSize,
+	}
+
+	var buf bytes.Buffer
+	if err := cryptdatum.EncodeHeader(&buf, &header); err != nil {
+		return name, err
+	}
 
-	// Set flags
-	flagDatumEmpty := 4
-	flagDatumChecksum := 8
-	flagDatumOPC := 16
-	flagDatumCompressed := 32
-	flagDatumEncrypted := 64
-	flagDatumExtractable := 128
-	flagDatumSigned := 256
-	flagDatumCustom := 1024
+	return writeFixture(name, buf.Bytes()[:cryptdatum.HeaderSize/2])
+}
+
+// createTampered outputs an otherwise valid header with a single byte
+// inside the delimiter flipped, for testing that corruption is rejected.
+func createTampered() (string, error) {
+	const name = "tampered.cdt"
 
-	flag := uint64(flagDatumEmpty | flagDatumChecksum | flagDatumOPC | flagDatumEncrypted | flagDatumCompressed | flagDatumSigned | flagDatumCustom | flagDatumExtractable)
-	binary.LittleEndian.PutUint64(header[10:18], flag)
+	header := cryptdatum.Header{
+		Version:   cryptdatum.Version,
+		Flags:     cryptdatum.DatumEmpty,
+		Timestamp: uint64(time.Date(2022, 5, 10, 4, 3, 2, 1, time.UTC).UnixNano()),
+		Size:      cryptdatum.HeaderSize,
+	}
 
-	// Set unix time in nanoseconds
-	binary.LittleEndian.PutUint64(header[18:26], uint64(ts))
+	var buf bytes.Buffer
+	if err := cryptdatum.EncodeHeader(&buf, &header); err != nil {
+		return name, err
+	}
+	tampered := buf.Bytes()
+	tampered[75] ^= 0xFF
 
-	// Op counter
-	binary.LittleEndian.PutUint32(header[26:30], 2)
+	return writeFixture(name, tampered)
+}
 
-	// Checksum
-	copy(header[30:38], []byte{'c', 'h', 'e', 'c', 'k', 's', 'u', 'm'})
+// createCompressed outputs a datum whose payload is flate-compressed, for
+// testing the Writer/registry compression path.
+func createCompressed() (string, error) {
+	const name = "compressed.cdt"
+	const flateID = 1
+	payload := []byte("hello cryptdatum compressed fixture")
+
+	header := cryptdatum.Header{
+		Version:        cryptdatum.Version,
+		Flags:          cryptdatum.DatumCompressed,
+		Timestamp:      uint64(time.Date(2022, 5, 10, 4, 3, 2, 1, time.UTC).UnixNano()),
+		CompressionAlg: flateID,
+	}
 
-	// Size
-	binary.LittleEndian.PutUint64(header[38:46], 3)
+	var buf bytes.Buffer
+	w, err := cryptdatum.NewWriter(&buf, header)
+	if err != nil {
+		return name, err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return name, err
+	}
+	if err := w.Close(); err != nil {
+		return name, err
+	}
+
+	return writeFixture(name, buf.Bytes())
+}
 
-	// Compression Algorithm
-	binary.LittleEndian.PutUint16(header[46:48], 4)
+// createEncrypted outputs a datum whose payload is AES-GCM encrypted, for
+// testing the Writer/registry encryption path. Regenerate it by running
+// this generator with the Go toolchain; its output depends on the aesgcm
+// package and cannot be hand-derived.
+func createEncrypted() (string, error) {
+	const name = "encrypted.cdt"
+	const aesgcmID = 1
+	payload := []byte("hello cryptdatum encrypted fixture")
+	key := bytes.Repeat([]byte{0x24}, 32)
+
+	header := cryptdatum.Header{
+		Version:       cryptdatum.Version,
+		Flags:         cryptdatum.DatumEncrypted,
+		Timestamp:     uint64(time.Date(2022, 5, 10, 4, 3, 2, 1, time.UTC).UnixNano()),
+		EncryptionAlg: aesgcmID,
+	}
 
-	// Encryption Algorithm
-	binary.LittleEndian.PutUint16(header[48:50], 5)
+	// aesgcm generates and stores its own per-datum nonce, so no IV is
+	// supplied here: a caller-managed one could be reused across datums,
+	// which breaks AES-GCM's confidentiality and forgery resistance.
+	var buf bytes.Buffer
+	w, err := cryptdatum.NewWriter(&buf, header, cryptdatum.WithEncryptionKey(key, nil))
+	if err != nil {
+		return name, err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return name, err
+	}
+	if err := w.Close(); err != nil {
+		return name, err
+	}
 
-	// Signature Type
-	binary.LittleEndian.PutUint16(header[50:52], 6)
+	return writeFixture(name, buf.Bytes())
+}
 
-	// Signature Size
-	binary.LittleEndian.PutUint32(header[52:56], 7)
+// createSigned outputs a datum whose payload is Ed25519-signed, for testing
+// the Writer/registry signing path. Regenerate it by running this generator
+// with the Go toolchain; its output depends on the ed25519 package and
+// cannot be hand-derived.
+func createSigned() (string, error) {
+	const name = "signed.cdt"
+	const ed25519ID = 1
+	payload := []byte("hello cryptdatum signed fixture")
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return name, err
+	}
 
-	// File extension
-	copy(header[56:64], []byte{'a', 'f', 'f', 'i', 'x', 'i', 'n', 'g'})
+	header := cryptdatum.Header{
+		Version:       cryptdatum.Version,
+		Flags:         cryptdatum.DatumSigned,
+		Timestamp:     uint64(time.Date(2022, 5, 10, 4, 3, 2, 1, time.UTC).UnixNano()),
+		SignatureType: ed25519ID,
+	}
 
-	// Custom data
-	copy(header[64:72], []byte{'t', 'a', 'i', 'l', 'o', 'r', 'e', 'd'})
+	var buf bytes.Buffer
+	w, err := cryptdatum.NewWriter(&buf, header, cryptdatum.WithSignatureKey(priv))
+	if err != nil {
+		return name, err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return name, err
+	}
+	if err := w.Close(); err != nil {
+		return name, err
+	}
 
-	// delimiter
-	copy(header[72:80], delimiter[:])
+	// The public key is persisted alongside the datum so tests can verify
+	// the fixture's signature without regenerating the keypair.
+	if _, err := writeFixture("signed.pub", pub); err != nil {
+		return name, err
+	}
 
-	return name, os.WriteFile(name, header[:], 0640)
+	return writeFixture(name, buf.Bytes())
 }
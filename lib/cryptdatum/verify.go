@@ -0,0 +1,49 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package cryptdatum
+
+import (
+	"fmt"
+	"hash/crc64"
+	"io"
+)
+
+// ErrChecksumMismatch is returned by Verify when a datum has DatumChecksum
+// set and its computed CRC64 checksum does not match header.Checksum.
+var ErrChecksumMismatch = fmt.Errorf("%w: checksum mismatch", Err)
+
+// crc64Tables maps a future checksum-algorithm header field to the CRC64
+// table it selects, so ISO and ECMA (and others) can be chosen between
+// without a format break. Only ISO is reachable today, matching the
+// "CRC64" checksum documented on Header; the table is here so Verify
+// already knows how to pick a different one once that field exists.
+var crc64Tables = map[uint16]*crc64.Table{
+	0: crc64Table, // ISO, the only algorithm in use today
+	1: crc64.MakeTable(crc64.ECMA),
+}
+
+// Verify decodes the header from r and streams the rest of the datum to
+// compute its CRC64 checksum. If DatumChecksum is not set on the header,
+// Verify returns the header without computing anything. Otherwise, it
+// returns ErrChecksumMismatch when the computed checksum does not match
+// header.Checksum.
+func Verify(r io.Reader) (Header, error) {
+	header, err := DecodeHeader(r)
+	if err != nil {
+		return header, err
+	}
+	if header.Flags&DatumChecksum == 0 {
+		return header, nil
+	}
+
+	h := crc64.New(crc64Tables[0])
+	if _, err := io.Copy(h, r); err != nil {
+		return header, err
+	}
+	if h.Sum64() != header.Checksum {
+		return header, ErrChecksumMismatch
+	}
+	return header, nil
+}
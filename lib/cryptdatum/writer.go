@@ -0,0 +1,310 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package cryptdatum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+)
+
+var (
+	ErrInvalidVersion        = fmt.Errorf("%w: version is lower than MinVersion", Err)
+	ErrInvalidTimestamp      = fmt.Errorf("%w: timestamp is older than magicDate", Err)
+	ErrMissingChecksum       = fmt.Errorf("%w: DatumChecksum is set but Checksum is not", Err)
+	ErrMissingOPC            = fmt.Errorf("%w: DatumOPC is set but OPC is not", Err)
+	ErrMissingSize           = fmt.Errorf("%w: DatumEmpty is set but Size is not", Err)
+	ErrMissingCompressionAlg = fmt.Errorf("%w: DatumCompressed is set but CompressionAlg is not", Err)
+	ErrMissingEncryptionAlg  = fmt.Errorf("%w: DatumEncrypted is set but EncryptionAlg is not", Err)
+	ErrMissingFileExt        = fmt.Errorf("%w: DatumExtractable is set but FileExt is not", Err)
+	ErrMissingSignatureType  = fmt.Errorf("%w: DatumSigned is set but SignatureType is not", Err)
+	ErrUnknownCompressionAlg = fmt.Errorf("%w: no Compressor/Decompressor registered for CompressionAlg", Err)
+	ErrUnknownEncryptionAlg  = fmt.Errorf("%w: no Encrypter/Decrypter registered for EncryptionAlg", Err)
+	ErrUnknownSignatureType  = fmt.Errorf("%w: no Signer/Verifier registered for SignatureType", Err)
+)
+
+// crc64Table is the table used to compute the Checksum field. It uses the
+// ISO polynomial, matching the "CRC64" checksum documented on Header.
+var crc64Table = crc64.MakeTable(crc64.ISO)
+
+// validateHeader checks that h satisfies the invariants HasValidHeader
+// expects an encoded header to hold, before any bytes are written for it.
+// It does not check Checksum's or Size's presence: those invariants only
+// apply once the fields have actually been computed, which for headers
+// going through Writer does not happen until Close. EncodeHeader, which
+// writes a header standalone with no payload to compute them from,
+// enforces those invariants itself.
+func validateHeader(h *Header) error {
+	if h.Version < MinVersion {
+		return ErrInvalidVersion
+	}
+	// DatumDraft and DatumCompromised headers are exempt from every other
+	// invariant below, mirroring HasValidHeader's own early return: a draft
+	// or compromised datum is allowed to carry incomplete or stale fields.
+	if h.Flags&DatumDraft != 0 || h.Flags&DatumCompromised != 0 {
+		return nil
+	}
+	if h.Timestamp < magicDate {
+		return ErrInvalidTimestamp
+	}
+	if h.Flags&DatumOPC != 0 && h.OPC < 1 {
+		return ErrMissingOPC
+	}
+	if h.Flags&DatumCompressed != 0 && h.CompressionAlg < 1 {
+		return ErrMissingCompressionAlg
+	}
+	if h.Flags&DatumEncrypted != 0 && h.EncryptionAlg < 1 {
+		return ErrMissingEncryptionAlg
+	}
+	if h.Flags&DatumExtractable != 0 && h.FileExt == "" {
+		return ErrMissingFileExt
+	}
+	if h.Flags&DatumSigned != 0 && h.SignatureType < 1 {
+		return ErrMissingSignatureType
+	}
+	return nil
+}
+
+// encodeHeaderBytes renders h as its canonical HeaderSize-byte encoding. The
+// magic number and delimiter are always written regardless of what h holds.
+func encodeHeaderBytes(h *Header) []byte {
+	b := make([]byte, HeaderSize)
+	copy(b[0:8], Magic[:])
+	binary.LittleEndian.PutUint16(b[8:10], h.Version)
+	binary.LittleEndian.PutUint64(b[10:18], h.Flags)
+	binary.LittleEndian.PutUint64(b[18:26], h.Timestamp)
+	binary.LittleEndian.PutUint32(b[26:30], h.OPC)
+	binary.LittleEndian.PutUint64(b[30:38], h.Checksum)
+	binary.LittleEndian.PutUint64(b[38:46], h.Size)
+	binary.LittleEndian.PutUint16(b[46:48], h.CompressionAlg)
+	binary.LittleEndian.PutUint16(b[48:50], h.EncryptionAlg)
+	binary.LittleEndian.PutUint16(b[50:52], h.SignatureType)
+	binary.LittleEndian.PutUint32(b[52:56], h.SignatureSize)
+	copy(b[56:64], []byte(h.FileExt))
+	copy(b[64:72], h.Custom[:])
+	copy(b[72:80], Delimiter[:])
+	return b
+}
+
+// EncodeHeader validates h against the invariants HasValidHeader enforces
+// and writes its canonical HeaderSize-byte encoding to w. It does not write
+// any payload; use Writer to stream a full datum and have Size and, when
+// DatumChecksum is set, Checksum filled in automatically.
+func EncodeHeader(w io.Writer, h *Header) error {
+	if err := validateHeader(h); err != nil {
+		return err
+	}
+	draft := h.Flags&DatumDraft != 0 || h.Flags&DatumCompromised != 0
+	if !draft && h.Flags&DatumChecksum != 0 && h.Checksum == 0 {
+		return ErrMissingChecksum
+	}
+	if !draft && h.Flags&DatumEmpty != 0 && h.Size < 1 {
+		return ErrMissingSize
+	}
+	_, err := w.Write(encodeHeaderBytes(h))
+	return err
+}
+
+// countingWriter forwards writes to w, maintaining a running CRC64 (when
+// crc is set) and byte count of everything that passes through it. It sits
+// at the bottom of a Writer's chain, so it sees the bytes as they will
+// actually be stored: post-compression, post-encryption, post-signing.
+type countingWriter struct {
+	w   io.Writer
+	crc hash.Hash64
+	n   uint64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.crc != nil {
+		c.crc.Write(p)
+	}
+	c.n += uint64(len(p))
+	return c.w.Write(p)
+}
+
+// WriterOption configures a Writer's handling of algorithms that need key
+// material the Header itself does not carry.
+type WriterOption func(*Writer)
+
+// WithEncryptionKey supplies the key and IV an Encrypter registered for
+// header.EncryptionAlg needs when DatumEncrypted is set.
+func WithEncryptionKey(key, iv []byte) WriterOption {
+	return func(cw *Writer) {
+		cw.encKey, cw.encIV = key, iv
+	}
+}
+
+// WithSignatureKey supplies the key a Signer registered for
+// header.SignatureType needs when DatumSigned is set.
+func WithSignatureKey(key []byte) WriterOption {
+	return func(cw *Writer) {
+		cw.sigKey = key
+	}
+}
+
+// Writer writes a Cryptdatum stream: a header followed by its payload. The
+// header can only be finalized once the payload has been fully written, so
+// Writer seeks back to patch it in on an io.WriteSeeker, or buffers the
+// payload and writes the header followed by the buffer otherwise.
+//
+// When DatumCompressed, DatumEncrypted and/or DatumSigned are set on the
+// header, payload bytes are routed through the Compressor, Encrypter and
+// Signer registered for the header's CompressionAlg, EncryptionAlg and
+// SignatureType, in that order, so the stored stream is
+// compressed-then-encrypted-then-signed.
+type Writer struct {
+	w      io.Writer
+	ws     io.WriteSeeker
+	header Header
+	buf    *bytes.Buffer
+	sink   *countingWriter
+	dest   io.Writer
+	chain  []io.Closer
+	encKey []byte
+	encIV  []byte
+	sigKey []byte
+	closed bool
+
+	// streaming mode (DatumStreamable): payload is buffered into
+	// StreamSegmentSize-sized chunks, each independently run through the
+	// compressor/encrypter and framed, instead of being routed through a
+	// single continuous chain. See stream.go.
+	streaming bool
+	segBuf    []byte
+	segHashes [][sha256.Size]byte
+}
+
+// NewWriter validates header and returns a Writer that streams a Cryptdatum
+// payload for it to w. The caller does not need to set header.Size or, when
+// DatumChecksum is set, header.Checksum: Writer computes both as the payload
+// is written and patches them in on Close.
+func NewWriter(w io.Writer, header Header, opts ...WriterOption) (*Writer, error) {
+	if err := validateHeader(&header); err != nil {
+		return nil, err
+	}
+	cw := &Writer{w: w, header: header}
+	for _, opt := range opts {
+		opt(cw)
+	}
+	if ws, ok := w.(io.WriteSeeker); ok {
+		cw.ws = ws
+		if _, err := ws.Write(make([]byte, HeaderSize)); err != nil {
+			return nil, err
+		}
+		cw.sink = &countingWriter{w: ws}
+	} else {
+		cw.buf = new(bytes.Buffer)
+		cw.sink = &countingWriter{w: cw.buf}
+	}
+	if header.Flags&DatumChecksum != 0 {
+		cw.sink.crc = crc64.New(crc64Table)
+	}
+
+	if header.Flags&DatumStreamable != 0 {
+		cw.streaming = true
+		cw.dest = cw.sink
+		return cw, nil
+	}
+
+	// Wrapped innermost-first (closest to sink) so that the outermost
+	// wrapper, which ends up as cw.dest and therefore sees Write's plaintext
+	// first, is the compressor: plaintext flows compressor -> encrypter ->
+	// signer -> sink, giving the advertised compressed-then-encrypted-
+	// then-signed stream on the wire.
+	var dest io.Writer = cw.sink
+	if header.Flags&DatumSigned != 0 {
+		fn, ok := signerFor(header.SignatureType)
+		if !ok {
+			return nil, ErrUnknownSignatureType
+		}
+		wc, err := fn(cw.sigKey, dest)
+		if err != nil {
+			return nil, err
+		}
+		cw.chain = append(cw.chain, wc)
+		dest = wc
+	}
+	if header.Flags&DatumEncrypted != 0 {
+		fn, ok := encrypterFor(header.EncryptionAlg)
+		if !ok {
+			return nil, ErrUnknownEncryptionAlg
+		}
+		wc, err := fn(cw.encKey, cw.encIV, dest)
+		if err != nil {
+			return nil, err
+		}
+		cw.chain = append(cw.chain, wc)
+		dest = wc
+	}
+	if header.Flags&DatumCompressed != 0 {
+		fn, ok := compressorFor(header.CompressionAlg)
+		if !ok {
+			return nil, ErrUnknownCompressionAlg
+		}
+		wc := fn(dest)
+		cw.chain = append(cw.chain, wc)
+		dest = wc
+	}
+	cw.dest = dest
+	return cw, nil
+}
+
+// Write appends payload bytes to the datum. Unless DatumStreamable is set,
+// they are routed through any registered compressor/encrypter/signer chain
+// before reaching the running checksum and byte counter used to finalize
+// Header.Size on Close; see stream.go for DatumStreamable's segment framing.
+func (cw *Writer) Write(p []byte) (int, error) {
+	if cw.streaming {
+		return cw.writeSegments(p)
+	}
+	return cw.dest.Write(p)
+}
+
+// Close flushes any buffered payload (as stream segments, or through the
+// compressor/encrypter/signer chain in reverse order), finalizes the header
+// with the total stored size and, when DatumChecksum is set, the computed
+// CRC64 checksum, then writes it to the underlying writer. No more payload
+// may be written after Close.
+func (cw *Writer) Close() error {
+	if cw.closed {
+		return nil
+	}
+	cw.closed = true
+
+	if cw.streaming {
+		if err := cw.closeStream(); err != nil {
+			return err
+		}
+	} else {
+		for i := len(cw.chain) - 1; i >= 0; i-- {
+			if err := cw.chain[i].Close(); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.header.Size = uint64(HeaderSize) + cw.sink.n
+	if cw.sink.crc != nil {
+		cw.header.Checksum = cw.sink.crc.Sum64()
+	}
+	headb := encodeHeaderBytes(&cw.header)
+	if cw.ws != nil {
+		if _, err := cw.ws.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		_, err := cw.ws.Write(headb)
+		return err
+	}
+	if _, err := cw.w.Write(headb); err != nil {
+		return err
+	}
+	_, err := cw.w.Write(cw.buf.Bytes())
+	return err
+}
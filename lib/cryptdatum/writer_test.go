@@ -0,0 +1,134 @@
+// Copyright 2022 The howijd.network Authors
+// Licensed under the Apache License, Version 2.0.
+// See the LICENSE file.
+
+package cryptdatum
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestEncodeHeaderRejectsInvalidVersion(t *testing.T) {
+	header := Header{Version: 0, Timestamp: magicDate}
+	var buf bytes.Buffer
+	if err := EncodeHeader(&buf, &header); err != ErrInvalidVersion {
+		t.Errorf("expected ErrInvalidVersion, got %v", err)
+	}
+}
+
+func TestEncodeHeaderRejectsOldTimestamp(t *testing.T) {
+	header := Header{Version: Version, Timestamp: magicDate - 1}
+	var buf bytes.Buffer
+	if err := EncodeHeader(&buf, &header); err != ErrInvalidTimestamp {
+		t.Errorf("expected ErrInvalidTimestamp, got %v", err)
+	}
+}
+
+func TestEncodeHeaderAllowsDraftWithoutTimestamp(t *testing.T) {
+	header := Header{Version: Version, Flags: DatumDraft}
+	var buf bytes.Buffer
+	if err := EncodeHeader(&buf, &header); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !HasValidHeader(buf.Bytes()) {
+		t.Errorf("expected encoded header to be valid")
+	}
+}
+
+func TestEncodeHeaderRejectsMissingChecksum(t *testing.T) {
+	header := Header{Version: Version, Flags: DatumChecksum, Timestamp: magicDate}
+	var buf bytes.Buffer
+	if err := EncodeHeader(&buf, &header); err != ErrMissingChecksum {
+		t.Errorf("expected ErrMissingChecksum, got %v", err)
+	}
+}
+
+func TestWriterWritesPayloadAndFinalizesHeader(t *testing.T) {
+	payload := []byte("hello cryptdatum")
+	header := Header{Version: Version, Flags: DatumChecksum, Timestamp: magicDate}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := buf.Bytes()
+	if !HasValidHeader(out) {
+		t.Errorf("expected encoded stream to have a valid header")
+	}
+
+	got, err := DecodeHeader(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Size != uint64(HeaderSize+len(payload)) {
+		t.Errorf("expected Size %d, got %d", HeaderSize+len(payload), got.Size)
+	}
+	if got.Checksum == 0 {
+		t.Errorf("expected Checksum to be computed")
+	}
+	if !bytes.Equal(out[HeaderSize:], payload) {
+		t.Errorf("expected payload to follow the header unmodified")
+	}
+}
+
+// TestWriterSeeksBackToPatchHeaderOnWriteSeeker exercises the
+// io.WriteSeeker branch of NewWriter with a real *os.File rather than a
+// plain io.Writer, so the seek-back-and-patch path it takes instead of
+// buffering is actually covered.
+func TestWriterSeeksBackToPatchHeaderOnWriteSeeker(t *testing.T) {
+	payload := []byte("hello cryptdatum write-seeker")
+	header := Header{Version: Version, Flags: DatumChecksum, Timestamp: magicDate}
+
+	f, err := os.CreateTemp(t.TempDir(), "cryptdatum-writeseeker-*.cdt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	w, err := NewWriter(f, header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !HasValidHeader(out) {
+		t.Errorf("expected encoded stream to have a valid header")
+	}
+	got, err := DecodeHeader(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Size != uint64(HeaderSize+len(payload)) {
+		t.Errorf("expected Size %d, got %d", HeaderSize+len(payload), got.Size)
+	}
+	if got.Checksum == 0 {
+		t.Errorf("expected Checksum to be computed")
+	}
+	if !bytes.Equal(out[HeaderSize:], payload) {
+		t.Errorf("expected payload to follow the header unmodified")
+	}
+}